@@ -0,0 +1,30 @@
+// Package conf 定义 cronsun 各个角色（node/web）共用的配置结构。
+package conf
+
+// Conf 是进程启动时从配置文件加载的配置。
+type Conf struct {
+	// Ttl 是结点在 etcd 里注册信息的租约时长，单位秒
+	Ttl int64
+
+	// GitSync 配置一个或多个用 Git 仓库管理 job 定义的同步源，为空表示不启用
+	GitSync []*GitSyncRepo
+}
+
+// GitSyncRepo 描述一个承载 job/group 定义文件的 Git 仓库。
+type GitSyncRepo struct {
+	URL    string
+	Branch string
+	// Path 是仓库内存放任务定义文件的子目录，留空表示仓库根目录
+	Path string
+	// LocalDir 是该仓库 clone 到本地的工作目录
+	LocalDir string
+	// IntervalSeconds 是两次同步之间的间隔，<=0 时取默认值
+	IntervalSeconds int
+
+	// 以下二选一：配置了 SSHKeyFile 走 ssh deploy key，否则如果配置了
+	// Password 走 http basic auth，都未配置则认为是公开仓库
+	SSHKeyFile     string
+	SSHKeyPassword string
+	User           string
+	Password       string
+}
@@ -0,0 +1,341 @@
+// Package gitsync 实现把 Git 仓库中的任务定义文件同步到 etcd 的能力，
+// 让 job/group 可以通过提交 yaml/json 文件来管理，而不仅仅依赖 web 后台。
+package gitsync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3/concurrency"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	yaml "gopkg.in/yaml.v2"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/conf"
+	"github.com/shunfei/cronsun/models"
+)
+
+const (
+	// 状态信息存放的 etcd 前缀，供 web 展示同步状态
+	statusDir = "/cronsun/gitsync/status/"
+
+	jobFileSuffix   = ".yml"
+	jobFileSuffix2  = ".yaml"
+	jobFileSuffix3  = ".json"
+	groupFilePrefix = "group."
+
+	// electionPrefix 下只会有一个结点选主成功，避免所有结点并发
+	// clone/pull 同一个仓库、并发往 etcd 写 reconcile 结果
+	electionPrefix = "/cronsun/gitsync/leader"
+	electionTTL    = 15
+)
+
+// GitSync 负责按配置的周期拉取一个或多个 job 定义仓库，
+// 并把内容同步（diff + reconcile）到 etcd 中的 job/group。
+// 集群里所有结点都会启动 GitSync，但只有通过 etcd 选主选出来的那一个
+// 会真正执行同步，其余结点处于待命状态，这样仓库只会被 clone/pull 一次，
+// reconcile 的 create/update/delete 也不会在结点之间互相竞争。
+type GitSync struct {
+	cfg  []*conf.GitSyncRepo
+	id   string
+	done chan struct{}
+
+	// lastSHA 记录每个仓库最近一次成功同步的 commit sha，一次失败的同步
+	// 不应该把这个值抹掉——reportStatus 在出错时仍然要带着它一起上报，
+	// 不然 web 只看得到最新的错误，连"上一次成功同步到哪个 commit"都查
+	// 不到了。
+	mu      sync.Mutex
+	lastSHA map[string]string
+}
+
+// New 根据配置创建 GitSync，cfg 为空时返回 nil，调用方应跳过 Run。
+// id 是当前结点的标识，用于参与选主。
+func New(id string, cfg []*conf.GitSyncRepo) *GitSync {
+	if len(cfg) == 0 {
+		return nil
+	}
+
+	return &GitSync{
+		cfg:     cfg,
+		id:      id,
+		done:    make(chan struct{}),
+		lastSHA: make(map[string]string, len(cfg)),
+	}
+}
+
+// Run 参与选主，选上之后才真正启动所有配置仓库的同步循环；落选或者还
+// 没选出结果之前什么都不做，阻塞直到 Stop 被调用。
+func (gs *GitSync) Run() {
+	sess, err := concurrency.NewSession(models.DefalutClient.Client, concurrency.WithTTL(electionTTL))
+	if err != nil {
+		log.Warnf("gitsync: new session err: %s", err.Error())
+		return
+	}
+	defer sess.Close()
+
+	election := concurrency.NewElection(sess, electionPrefix)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-gs.done
+		cancel()
+	}()
+
+	if err := election.Campaign(ctx, gs.id); err != nil {
+		if ctx.Err() == nil {
+			log.Warnf("gitsync: campaign err: %s", err.Error())
+		}
+		return
+	}
+	defer election.Resign(context.Background())
+
+	log.Noticef("gitsync: %s elected as sync leader", gs.id)
+	for _, repo := range gs.cfg {
+		go gs.loop(ctx, repo)
+	}
+	<-ctx.Done()
+}
+
+// Stop 结束同步循环，如果当前结点是 leader 也会主动 resign。
+func (gs *GitSync) Stop() {
+	close(gs.done)
+}
+
+func (gs *GitSync) loop(ctx context.Context, repo *conf.GitSyncRepo) {
+	interval := time.Duration(repo.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	timer := time.NewTimer(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := gs.sync(repo); err != nil {
+				log.Warnf("gitsync[%s] err: %s", repo.URL, err.Error())
+				gs.reportStatus(repo, gs.getLastSHA(repo), err)
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// sync 克隆/更新仓库、解析任务文件并把差异落到 etcd。
+func (gs *GitSync) sync(repo *conf.GitSyncRepo) error {
+	r, err := gs.open(repo)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	sha := head.Hash().String()
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	dir := w.Filesystem.Root()
+	if repo.Path != "" {
+		dir = filepath.Join(dir, repo.Path)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	seenJobs := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		name := f.Name()
+		if !strings.HasSuffix(name, jobFileSuffix) && !strings.HasSuffix(name, jobFileSuffix2) && !strings.HasSuffix(name, jobFileSuffix3) {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			log.Warnf("gitsync[%s] read %s err: %s", repo.URL, name, err.Error())
+			continue
+		}
+
+		if strings.HasPrefix(name, groupFilePrefix) {
+			if err := gs.reconcileGroup(b); err != nil {
+				log.Warnf("gitsync[%s] group %s err: %s", repo.URL, name, err.Error())
+			}
+			continue
+		}
+
+		job, err := gs.reconcileJob(repo, b)
+		if err != nil {
+			log.Warnf("gitsync[%s] job %s err: %s", repo.URL, name, err.Error())
+			continue
+		}
+		seenJobs[job.ID] = true
+	}
+
+	if err := gs.pruneJobs(repo, seenJobs); err != nil {
+		log.Warnf("gitsync[%s] prune err: %s", repo.URL, err.Error())
+	}
+
+	gs.setLastSHA(repo, sha)
+	gs.reportStatus(repo, sha, nil)
+	return nil
+}
+
+func (gs *GitSync) getLastSHA(repo *conf.GitSyncRepo) string {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.lastSHA[repo.URL]
+}
+
+func (gs *GitSync) setLastSHA(repo *conf.GitSyncRepo, sha string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.lastSHA[repo.URL] = sha
+}
+
+// open 返回本地已 clone 好的仓库，首次同步时执行 clone，之后执行 pull。
+func (gs *GitSync) open(repo *conf.GitSyncRepo) (*git.Repository, error) {
+	auth, err := gs.auth(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := git.PlainOpen(repo.LocalDir)
+	if err == git.ErrRepositoryNotExists {
+		return git.PlainClone(repo.LocalDir, false, &git.CloneOptions{
+			URL:           repo.URL,
+			Auth:          auth,
+			ReferenceName: branchRef(repo.Branch),
+			SingleBranch:  true,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Pull(&git.PullOptions{Auth: auth, ReferenceName: branchRef(repo.Branch), SingleBranch: true})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (gs *GitSync) auth(repo *conf.GitSyncRepo) (transport.AuthMethod, error) {
+	switch {
+	case repo.SSHKeyFile != "":
+		return ssh.NewPublicKeysFromFile("git", repo.SSHKeyFile, repo.SSHKeyPassword)
+	case repo.Password != "":
+		return &http.BasicAuth{Username: repo.User, Password: repo.Password}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// reconcileJob 把 yaml/json 内容解析成 job，和 etcd 中现有的 job diff 后写回。
+// JSON 是 YAML 的子集，yaml.Unmarshal 可以直接解析 .json 文件，不需要
+// 按后缀区分走两条不同的解析路径。
+func (gs *GitSync) reconcileJob(repo *conf.GitSyncRepo, b []byte) (*models.Job, error) {
+	job := new(models.Job)
+	if err := yaml.Unmarshal(b, job); err != nil {
+		return nil, err
+	}
+
+	if job.ID == "" {
+		return nil, fmt.Errorf("job missing id")
+	}
+
+	// 标记这个 job 是由哪个仓库同步进来的，pruneJobs 靠这个字段判断
+	// 文件被删除之后能不能跟着删 job（见 models.Job.Source 的注释）。
+	job.Source = repo.URL
+
+	old, err := models.GetJob(job.Group, job.ID)
+	if err != nil && err != models.ErrNotFound {
+		return nil, err
+	}
+
+	if old != nil && old.Equal(job) {
+		return job, nil
+	}
+
+	if _, err := job.Put(nil); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (gs *GitSync) reconcileGroup(b []byte) error {
+	g := new(models.Group)
+	if err := yaml.Unmarshal(b, g); err != nil {
+		return err
+	}
+
+	if g.ID == "" {
+		return fmt.Errorf("group missing id")
+	}
+
+	_, err := g.Put(nil)
+	return err
+}
+
+// pruneJobs 删除仓库里已经不存在、但之前是由本仓库同步进来的 job。
+func (gs *GitSync) pruneJobs(repo *conf.GitSyncRepo, seen map[string]bool) error {
+	jobs, err := models.GetJobs()
+	if err != nil {
+		return err
+	}
+
+	for id, job := range jobs {
+		if job.Source != repo.URL || seen[id] {
+			continue
+		}
+
+		if err := job.Delete(); err != nil {
+			log.Warnf("gitsync[%s] delete job[%s] err: %s", repo.URL, id, err.Error())
+		}
+	}
+	return nil
+}
+
+// reportStatus 把最近一次同步的结果写入 etcd，供 web 展示。
+func (gs *GitSync) reportStatus(repo *conf.GitSyncRepo, sha string, syncErr error) {
+	status := fmt.Sprintf("time=%d sha=%s", time.Now().Unix(), sha)
+	if syncErr != nil {
+		status += " err=" + syncErr.Error()
+	}
+
+	key := statusDir + repo.URL
+	if _, err := models.DefalutClient.Put(context.Background(), key, status); err != nil {
+		log.Warnf("gitsync report status[%s] err: %s", repo.URL, err.Error())
+	}
+}
+
+func branchRef(branch string) plumbing.ReferenceName {
+	if branch == "" {
+		branch = "master"
+	}
+	return plumbing.ReferenceName("refs/heads/" + branch)
+}
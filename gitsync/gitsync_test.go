@@ -0,0 +1,90 @@
+package gitsync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+
+	"github.com/shunfei/cronsun/conf"
+	"github.com/shunfei/cronsun/models"
+)
+
+// newTestEtcdClient 连接本地 etcd，连不上就跳过测试——reconcileJob 会读
+// 写 etcd，这是一个需要真实 etcd 的集成测试（默认 127.0.0.1:2379）。
+func newTestEtcdClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	cli, err := client.New(client.Config{
+		Endpoints:   []string{"127.0.0.1:2379"},
+		DialTimeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Skipf("etcd not available: %s", err)
+	}
+
+	if _, err := cli.Get(context.Background(), "health-check"); err != nil {
+		t.Skipf("etcd not available: %s", err)
+	}
+	return cli
+}
+
+// TestReconcileJobYAMLSnakeCase 验证一份用 snake_case key（和 etcd JSON、
+// web 后台一致的 wire 格式）写的 job 文件，经 reconcileJob 解析后，
+// Rules[].NodeIDs/GroupIDs 等字段真的被填上了，而不是被 yaml.v2 默认的
+// 无下划线字段名规则悄悄丢弃成空值。
+func TestReconcileJobYAMLSnakeCase(t *testing.T) {
+	cli := newTestEtcdClient(t)
+	defer cli.Close()
+	models.DefalutClient = &models.Client{Client: cli}
+
+	const doc = `
+id: job-1
+group: g1
+name: demo job
+command: echo hi
+rules:
+  - id: r1
+    node_ids:
+      - node-1
+      - node-2
+    group_ids:
+      - group-1
+    exclude_node_ids:
+      - node-3
+    timer: "*/5 * * * *"
+depends_on:
+  - job-0
+`
+
+	gs := &GitSync{id: "test-node"}
+	repo := &conf.GitSyncRepo{URL: "https://example.com/jobs.git"}
+
+	job, err := gs.reconcileJob(repo, []byte(doc))
+	if err != nil {
+		t.Fatalf("reconcileJob err: %s", err)
+	}
+	defer job.Delete()
+
+	if job.ID != "job-1" || job.Group != "g1" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if len(job.DependsOn) != 1 || job.DependsOn[0] != "job-0" {
+		t.Fatalf("DependsOn not populated: %+v", job.DependsOn)
+	}
+	if len(job.Rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(job.Rules))
+	}
+
+	r := job.Rules[0]
+	if len(r.NodeIDs) != 2 || r.NodeIDs[0] != "node-1" || r.NodeIDs[1] != "node-2" {
+		t.Fatalf("NodeIDs not populated: %+v", r.NodeIDs)
+	}
+	if len(r.GroupIDs) != 1 || r.GroupIDs[0] != "group-1" {
+		t.Fatalf("GroupIDs not populated: %+v", r.GroupIDs)
+	}
+	if len(r.ExcludeNodeIDs) != 1 || r.ExcludeNodeIDs[0] != "node-3" {
+		t.Fatalf("ExcludeNodeIDs not populated: %+v", r.ExcludeNodeIDs)
+	}
+}
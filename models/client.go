@@ -0,0 +1,24 @@
+// Package models 定义 cronsun 的核心数据结构（Node/Job/Cmd/Group 等）
+// 以及它们在 etcd/MongoDB 中的存取方式。
+package models
+
+import (
+	client "github.com/coreos/etcd/clientv3"
+)
+
+// etcd 中各类对象存放的 key 前缀
+const (
+	NodePrefix   = "/cronsun/node/"
+	JobPrefix    = "/cronsun/cmd/"
+	GroupPrefix  = "/cronsun/group/"
+	OnceDir      = "/cronsun/once/"
+	KillerPrefix = "/cronsun/killer/"
+)
+
+// Client 是对 etcd clientv3.Client 的薄封装，cronsun 内部一律通过它访问 etcd。
+type Client struct {
+	*client.Client
+}
+
+// DefalutClient 是进程启动时初始化好的全局 etcd 客户端。
+var DefalutClient *Client
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// CmdTracker 在一次 Cmd 执行开始/结束时收到通知，使调用方（Node）能够
+// 把正在跑的进程记录下来，以便之后响应强制终止请求。
+type CmdTracker interface {
+	// Track 在进程启动后立即调用，key 全局唯一地标识这一次执行实例
+	Track(key string, cancel context.CancelFunc, cmd *exec.Cmd)
+	// Untrack 在这次执行结束（正常退出/出错/被杀）后调用
+	Untrack(key string)
+}
+
+// Cmd 是 Job 的一条 Rule 在某个结点上具体要执行的实例，
+// 是 node/cron 真正拿去调度的对象。
+type Cmd struct {
+	*Job
+	JobRule *JobRule
+
+	// Tracker 由 Node.addCmd 注入，Run 执行期间用它登记/注销运行状态，
+	// 从而让 watchKill 能找到并杀掉这次执行。为空时表示不需要跟踪
+	// （比如测试场景），Run 会直接跳过 Track/Untrack。
+	Tracker CmdTracker `json:"-"`
+
+	// NodeID 是执行这个 Cmd 的结点 id，由 Node.addCmd 注入，WriteResult
+	// 上报结果时需要知道是哪个结点跑的。
+	NodeID string `json:"-"`
+}
+
+// GetID 返回 job+rule 维度的复合 key，用来在 Node.cmds 里去重/定位，
+// 同一个 job 的不同 rule 会产生不同的 Cmd。
+func (c *Cmd) GetID() string {
+	return c.Job.ID + "/" + c.JobRule.ID
+}
+
+// Run 以 shell 方式执行一次 Command，并在执行期间把自己登记到 Tracker，
+// 这样 watchKill 才有进程可杀。t 是 node/cron 调度触发这次执行的时间，
+// 同时被用作这次运行实例的 runID，让同一个 Cmd 的多次并发/先后执行互不覆盖。
+func (c *Cmd) Run(t time.Time) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.Command)
+	cmd.Env = append(cmd.Env, c.Env...)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	key := c.GetID() + "/" + strconv.FormatInt(t.UnixNano(), 10)
+	if err := cmd.Start(); err != nil {
+		c.WriteResult("", "", -1, err)
+		return
+	}
+
+	if c.Tracker != nil {
+		c.Tracker.Track(key, cancel, cmd)
+		defer c.Tracker.Untrack(key)
+	}
+
+	err := cmd.Wait()
+	exitCode := 0
+	if err != nil {
+		exitCode = -1
+		if ee, ok := err.(*exec.ExitError); ok {
+			exitCode = ee.ExitCode()
+		}
+	}
+
+	c.WriteResult(out.String(), "", exitCode, err)
+}
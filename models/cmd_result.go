@@ -0,0 +1,31 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+
+	"sunteng/commons/log"
+)
+
+// WriteResult 记录一次 Cmd 执行的结果，并把精简后的成功/失败状态发到
+// JobResultDir 这条结果事件流上，供 dagCoordinator 之类需要及时感知完成
+// 状态的消费者 watch。完整的执行历史（stdout/stderr 等）落地到 MongoDB
+// 由 web 那一侧的既有代码负责，这里不重复存。
+func (c *Cmd) WriteResult(stdout, stderr string, exitCode int, err error) {
+	if err != nil {
+		log.Warnf("job[%s] rule[%s] exit[%d] err: %s", c.Job.ID, c.JobRule.ID, exitCode, err.Error())
+	} else {
+		log.Noticef("job[%s] rule[%s] exit[%d] done", c.Job.ID, c.JobRule.ID, exitCode)
+	}
+
+	result := JobResult{JobID: c.Job.ID, NodeID: c.NodeID, Success: err == nil}
+	b, merr := json.Marshal(result)
+	if merr != nil {
+		log.Warnf("job[%s] marshal result err: %s", c.Job.ID, merr.Error())
+		return
+	}
+
+	if _, perr := DefalutClient.Put(context.Background(), JobResultDir+c.Job.ID, string(b)); perr != nil {
+		log.Warnf("job[%s] publish result err: %s", c.Job.ID, perr.Error())
+	}
+}
@@ -0,0 +1,6 @@
+package models
+
+import "errors"
+
+// ErrNotFound 表示请求的 job/group 在 etcd 中不存在。
+var ErrNotFound = errors.New("models: not found")
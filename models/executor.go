@@ -0,0 +1,63 @@
+package models
+
+// Job.Kind 的取值，决定 node.runCmd 把 Command 分发给哪个 Executor。
+// 空字符串（以及任何未列在这里的值，包括 "shell"）都走本机 shell 命令，
+// 这是历史上唯一支持的方式，所以没有单独声明一个 "shell" 常量。
+const (
+	JobKindHTTP   = "http"
+	JobKindGRPC   = "grpc"
+	JobKindDocker = "docker"
+)
+
+// DockerJob.PullPolicy 的取值。
+const (
+	PullPolicyAlways = "always"
+	PullPolicyNever  = "never"
+)
+
+// HTTPJob 描述一个 Kind=="http" 的 job 怎么发起请求、怎么判断成功。
+//
+// 同时带 json/yaml 标签，原因见 Job 的注释：gitsync 用 yaml.v2 解析仓库
+// 文件，标签名必须和 json 这边一致。
+type HTTPJob struct {
+	Method  string            `json:"method" yaml:"method"`
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Body 是一个 text/template 模板，渲染时可以用 job.Env 里的变量
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+	// SuccessStatus 是被视为成功的 HTTP 状态码列表，为空时默认 2xx 都算成功
+	SuccessStatus []int `json:"success_status,omitempty" yaml:"success_status,omitempty"`
+}
+
+// IsSuccess 判断一个 HTTP 状态码是否应该被当作执行成功。
+func (h *HTTPJob) IsSuccess(status int) bool {
+	if len(h.SuccessStatus) == 0 {
+		return status >= 200 && status < 300
+	}
+
+	for _, s := range h.SuccessStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GRPCJob 描述一个 Kind=="grpc" 的 job 要调用哪个服务/方法。
+type GRPCJob struct {
+	Target  string `json:"target" yaml:"target"`
+	Service string `json:"service" yaml:"service"`
+	Method  string `json:"method" yaml:"method"`
+	// Request 是 JSON 格式的请求体，通过 grpc-reflection 拿到的消息定义反射组装
+	Request string `json:"request,omitempty" yaml:"request,omitempty"`
+}
+
+// DockerJob 描述一个 Kind=="docker" 的 job 要跑什么容器。
+type DockerJob struct {
+	Image  string   `json:"image" yaml:"image"`
+	Cmd    []string `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	Env    []string `json:"env,omitempty" yaml:"env,omitempty"`
+	Mounts []string `json:"mounts,omitempty" yaml:"mounts,omitempty"`
+	// PullPolicy 为空或 PullPolicyAlways 时每次执行前都会先 pull 镜像
+	PullPolicy string `json:"pull_policy,omitempty" yaml:"pull_policy,omitempty"`
+}
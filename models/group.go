@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+
+	client "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// Group 是一组结点的集合，job 可以把 group 作为调度目标。
+//
+// 同时带 json/yaml 标签：etcd 存储走 json，gitsync 解析 group.*.yml 文件
+// 走 yaml.v2，两者标签名必须一致，否则 yaml.v2 会按全小写无下划线的字段
+// 名去找 key（如 nodeids），和 node_ids 对不上。
+type Group struct {
+	ID      string   `json:"id" yaml:"id"`
+	Name    string   `json:"name" yaml:"name"`
+	NodeIDs []string `json:"node_ids" yaml:"node_ids"`
+}
+
+// Groups 以 group id 为 key。
+type Groups map[string]*Group
+
+// Included 判断 nodeID 是否属于这个 group。
+func (g *Group) Included(nodeID string) bool {
+	for _, id := range g.NodeIDs {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Put 把 group 写入 etcd。
+func (g *Group) Put(opts ...client.OpOption) (*client.PutResponse, error) {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return DefalutClient.Put(context.Background(), GroupPrefix+g.ID, string(b), opts...)
+}
+
+// Delete 把 group 从 etcd 中移除。
+func (g *Group) Delete() error {
+	_, err := DefalutClient.Delete(context.Background(), GroupPrefix+g.ID)
+	return err
+}
+
+// GetGroups 取出所有以 prefix 开头的 group。
+func GetGroups(prefix string) (Groups, error) {
+	resp, err := DefalutClient.Get(context.Background(), GroupPrefix+prefix, client.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(Groups, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		g := new(Group)
+		if err := json.Unmarshal(kv.Value, g); err != nil {
+			continue
+		}
+		groups[g.ID] = g
+	}
+	return groups, nil
+}
+
+// GetGroupFromKv 把一条 etcd 的 group key/value 反序列化成 *Group。
+func GetGroupFromKv(kv *mvccpb.KeyValue) (*Group, error) {
+	g := new(Group)
+	if err := json.Unmarshal(kv.Value, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// WatchGroups 监听所有 group 的变更。
+func WatchGroups() client.WatchChan {
+	return DefalutClient.Watch(context.Background(), GroupPrefix, client.WithPrefix())
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+
+	client "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// Job 是 web 后台里用户配置的一个任务定义。
+//
+// 字段同时带 json 和 yaml 标签：etcd 里的存储格式是 json
+// （Put/jobFromBytes 用的是 encoding/json），但 gitsync 用 yaml.v2 解析
+// 仓库里的任务定义文件，yaml.v2 不认识 json 标签，没有 yaml 标签时会退化
+// 成全小写无下划线的字段名（如 nodeids），和这里统一用的 snake_case 对
+// 不上，导致文件里填 node_ids 之类的 key 会被静默丢弃。两个标签必须保持
+// 同名，否则同一个字段在 etcd JSON 和 gitsync YAML 里的 key 就不一致了。
+type Job struct {
+	ID      string     `json:"id" yaml:"id"`
+	Group   string     `json:"group" yaml:"group"`
+	Name    string     `json:"name" yaml:"name"`
+	Command string     `json:"command" yaml:"command"`
+	Env     []string   `json:"env,omitempty" yaml:"env,omitempty"`
+	Pause   bool       `json:"pause" yaml:"pause"`
+	Rules   []*JobRule `json:"rules" yaml:"rules"`
+
+	// Source 标记这个 job 是由哪个 gitsync 仓库同步进来的，空表示是通过
+	// web 后台手工创建的。gitsync 的 prune 逻辑靠这个字段判断一个 job
+	// 是否归它管，从而决定文件被删除时能不能跟着删 job。reconcileJob 会
+	// 在解析完文件后覆盖这个字段，文件里写了也没用，所以不暴露 yaml 标签。
+	Source string `json:"source,omitempty" yaml:"-"`
+
+	// Kind 决定 Command 到底怎么被执行，为空时按本机 shell 命令执行。
+	// node.runCmd 按这个字段分发到对应的 Executor。
+	Kind   string     `json:"kind,omitempty" yaml:"kind,omitempty"`
+	HTTP   *HTTPJob   `json:"http,omitempty" yaml:"http,omitempty"`
+	GRPC   *GRPCJob   `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	Docker *DockerJob `json:"docker,omitempty" yaml:"docker,omitempty"`
+
+	// DependsOn 是这个 job 的父任务 id 列表，全部在 dagWindow 内成功
+	// 完成后，node.dagCoordinator 才会触发本 job。
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	// TriggerOnSuccess 是本 job 成功完成后要触发的子任务 id 列表，纯粹
+	// 是给 web 后台展示 DAG 用的冗余字段，真正生效的是各子任务自己的
+	// DependsOn。
+	TriggerOnSuccess []string `json:"trigger_on_success,omitempty" yaml:"trigger_on_success,omitempty"`
+
+	// runOnID 是最近一次 RunOn 传入的结点 id，只做记录，不参与持久化；
+	// 执行范围由 IsRunOn/Cmds 的 nodeID 参数决定，不读这个字段
+	runOnID string
+}
+
+// Jobs 以 job id 为 key。
+type Jobs map[string]*Job
+
+// RunOn 把这份 job（通常是从 etcd watch 里拿到的最新版本）标记为正在
+// nodeID 这个结点上下文里处理。
+func (j *Job) RunOn(nodeID string) {
+	j.runOnID = nodeID
+}
+
+// IsRunOn 判断 job 是否有规则把 nodeID 当作执行目标。
+func (j *Job) IsRunOn(nodeID string, groups Groups) bool {
+	for _, r := range j.Rules {
+		if r.match(nodeID, groups) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cmds 返回这个 job 在 nodeID 上应该被调度的所有 Cmd，key 为 Cmd.GetID()。
+func (j *Job) Cmds(nodeID string, groups Groups) map[string]*Cmd {
+	cmds := make(map[string]*Cmd, len(j.Rules))
+	if j.Pause {
+		return cmds
+	}
+
+	for _, r := range j.Rules {
+		if !r.match(nodeID, groups) {
+			continue
+		}
+
+		cmd := &Cmd{Job: j, JobRule: r}
+		cmds[cmd.GetID()] = cmd
+	}
+	return cmds
+}
+
+// Equal 比较两个 job 在业务上是否等价（忽略 runOnID 这类运行时状态），
+// gitsync 用它来判断一次同步是否真的需要写 etcd。
+func (j *Job) Equal(other *Job) bool {
+	if j == nil || other == nil {
+		return j == other
+	}
+
+	a, err := json.Marshal(j)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(other)
+	if err != nil {
+		return false
+	}
+	return string(a) == string(b)
+}
+
+// AssignedNodeIDs 展开这个 job 所有 rule 的目标，返回它实际会跑在哪些
+// 结点上的去重列表。allNodeIDs 是集群里当前所有在线结点的 id，用来把
+// rule 里的 GroupIDs 展开成具体的结点集合。
+func (j *Job) AssignedNodeIDs(groups Groups, allNodeIDs []string) []string {
+	seen := make(map[string]bool, len(allNodeIDs))
+	for _, id := range allNodeIDs {
+		for _, r := range j.Rules {
+			if r.match(id, groups) {
+				seen[id] = true
+				break
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Put 把 job 写入 etcd，既可能是新建也可能是覆盖更新。
+func (j *Job) Put(opts ...client.OpOption) (*client.PutResponse, error) {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+	return DefalutClient.Put(context.Background(), JobPrefix+j.Group+"/"+j.ID, string(b), opts...)
+}
+
+// Delete 把 job 从 etcd 中移除，watchJobs 会据此触发各结点的 delJob。
+func (j *Job) Delete() error {
+	_, err := DefalutClient.Delete(context.Background(), JobPrefix+j.Group+"/"+j.ID)
+	return err
+}
+
+// GetJob 按 group+id 读取单个 job，不存在时返回 ErrNotFound。
+func GetJob(group, id string) (*Job, error) {
+	resp, err := DefalutClient.Get(context.Background(), JobPrefix+group+"/"+id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return jobFromBytes(resp.Kvs[0].Value)
+}
+
+// GetJobs 读取 etcd 里所有 job。
+func GetJobs() (Jobs, error) {
+	resp, err := DefalutClient.Get(context.Background(), JobPrefix, client.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(Jobs, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		job, err := jobFromBytes(kv.Value)
+		if err != nil {
+			continue
+		}
+		jobs[job.ID] = job
+	}
+	return jobs, nil
+}
+
+// GetJobFromKv 把一条 etcd 的 job key/value 反序列化成 *Job。
+func GetJobFromKv(kv *mvccpb.KeyValue) (*Job, error) {
+	return jobFromBytes(kv.Value)
+}
+
+func jobFromBytes(b []byte) (*Job, error) {
+	job := new(Job)
+	if err := json.Unmarshal(b, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// WatchJobs 监听所有 job 的变更。
+func WatchJobs() client.WatchChan {
+	return DefalutClient.Watch(context.Background(), JobPrefix, client.WithPrefix())
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	client "github.com/coreos/etcd/clientv3"
+)
+
+// JobResultDir 是结点上报执行结果流的 etcd 前缀。这是一个事件流，完整的
+// 执行历史仍然落在 MongoDB（web 后台查询用），这里只保留"最近一次"的
+// 结果，供 dagCoordinator 这类需要及时感知完成状态的消费者 watch。
+const JobResultDir = "/cronsun/result/"
+
+// JobResult 是一次 Cmd 执行完成后的精简结果，用于结果事件流。
+type JobResult struct {
+	JobID   string `json:"job_id"`
+	NodeID  string `json:"node_id"`
+	Success bool   `json:"success"`
+}
+
+// WatchJobResult 监听执行结果流。
+func WatchJobResult() <-chan *JobResult {
+	out := make(chan *JobResult)
+	rch := DefalutClient.Watch(context.Background(), JobResultDir, client.WithPrefix())
+
+	go func() {
+		defer close(out)
+		for wresp := range rch {
+			for _, ev := range wresp.Events {
+				if !ev.IsCreate() && !ev.IsModify() {
+					continue
+				}
+
+				r := new(JobResult)
+				if err := json.Unmarshal(ev.Kv.Value, r); err != nil {
+					continue
+				}
+				out <- r
+			}
+		}
+	}()
+
+	return out
+}
+
+// GetNodes 返回当前在线的所有结点 id。
+func GetNodes() ([]string, error) {
+	resp, err := DefalutClient.Get(context.Background(), NodePrefix, client.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		// 跳过 Node.On/Down 写的 <id>/status 这类子 key
+		if strings.Contains(strings.TrimPrefix(string(kv.Key), NodePrefix), "/") {
+			continue
+		}
+		ids = append(ids, GetIDFromKey(string(kv.Key)))
+	}
+	return ids, nil
+}
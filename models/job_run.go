@@ -0,0 +1,22 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+// RunWithContext 以 shell 方式执行一次 job.Command，受 ctx 控制超时/取消，
+// 返回合并后的 stdout+stderr。用于一次性（ad-hoc）执行场景，不经过
+// node/cron 的常规调度路径。
+func (j *Job) RunWithContext(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", j.Command)
+	cmd.Env = append(cmd.Env, j.Env...)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	return out.String(), err
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/shunfei/cronsun/node/cron"
+)
+
+// JobRule 描述一个 job 在哪些结点/group 上、按什么时间规则执行。
+//
+// 标签同时给 json 和 yaml：etcd 里用 json，gitsync 解析仓库文件用
+// yaml.v2，两者标签必须同名，否则 yaml.v2 会按全小写无下划线的字段名
+// 去找 key（如 nodeids），和这里统一用的 snake_case 对不上。
+type JobRule struct {
+	ID string `json:"id" yaml:"id"`
+
+	NodeIDs        []string `json:"node_ids,omitempty" yaml:"node_ids,omitempty"`
+	GroupIDs       []string `json:"group_ids,omitempty" yaml:"group_ids,omitempty"`
+	ExcludeNodeIDs []string `json:"exclude_node_ids,omitempty" yaml:"exclude_node_ids,omitempty"`
+
+	// Timer 是原始的 cron 表达式，主要用于日志展示
+	Timer string `json:"timer" yaml:"timer"`
+	// Schedule 是 Timer 解析后的结果，真正喂给 node/cron 使用
+	Schedule cron.Schedule `json:"-" yaml:"-"`
+
+	// Singleton 为 true 时，同一次调度在所有分配到的结点里只会有一个
+	// 真正执行，其余结点抢锁失败后静默跳过（见 node.singletonCmd）。
+	Singleton bool `json:"singleton,omitempty" yaml:"singleton,omitempty"`
+	// LockTTL 是 Singleton 抢的那把 etcd 锁的 lease TTL（秒）。持锁结点
+	// 在执行期间会不断续约，锁一直保持到执行结束；TTL 只决定持锁结点
+	// 失联（崩溃、断网）后锁还会保留多久才过期。<=0 时使用 node 包里的默认值。
+	LockTTL int64 `json:"lock_ttl,omitempty" yaml:"lock_ttl,omitempty"`
+}
+
+// match 判断该规则是否把 nodeID 当作执行目标。
+func (r *JobRule) match(nodeID string, groups Groups) bool {
+	for _, id := range r.ExcludeNodeIDs {
+		if id == nodeID {
+			return false
+		}
+	}
+
+	for _, id := range r.NodeIDs {
+		if id == nodeID {
+			return true
+		}
+	}
+
+	for _, gid := range r.GroupIDs {
+		if g, ok := groups[gid]; ok && g.Included(nodeID) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	client "github.com/coreos/etcd/clientv3"
+)
+
+// Node 是一个 cronsun 结点在 etcd 里的注册信息。
+type Node struct {
+	ID  string `json:"id"`
+	PID string `json:"pid"`
+}
+
+func (n *Node) String() string {
+	return fmt.Sprintf("node[%s] pid[%s]", n.ID, n.PID)
+}
+
+// Exist 查询该结点是否已经注册，返回已注册的 pid；未注册返回 -1。
+func (n *Node) Exist() (pid int, err error) {
+	resp, err := DefalutClient.Get(context.Background(), NodePrefix+n.ID)
+	if err != nil {
+		return -1, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return -1, nil
+	}
+
+	fmt.Sscanf(string(resp.Kvs[0].Value), "%d", &pid)
+	return pid, nil
+}
+
+// Put 把结点信息写入 etcd，opts 通常带上 WithLease 绑定租约。
+func (n *Node) Put(opts ...client.OpOption) (*client.PutResponse, error) {
+	return DefalutClient.Put(context.Background(), NodePrefix+n.ID, n.PID, opts...)
+}
+
+// On 把结点标记为在线。
+func (n *Node) On() {
+	DefalutClient.Put(context.Background(), NodePrefix+n.ID+"/status", "up")
+}
+
+// Down 把结点标记为离线。
+func (n *Node) Down() {
+	DefalutClient.Put(context.Background(), NodePrefix+n.ID+"/status", "down")
+}
+
+// Del 从 etcd 里彻底删除结点的注册信息。
+func (n *Node) Del() {
+	DefalutClient.Delete(context.Background(), NodePrefix+n.ID, client.WithPrefix())
+}
+
+// GetIDFromKey 从形如 "<prefix><id>" 的 etcd key 中取出 id 部分。
+func GetIDFromKey(key string) string {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] == '/' {
+			return key[i+1:]
+		}
+	}
+	return key
+}
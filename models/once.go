@@ -0,0 +1,17 @@
+package models
+
+import (
+	"context"
+
+	client "github.com/coreos/etcd/clientv3"
+)
+
+// WatchOnce 监听一次性执行请求目录 OnceDir。
+func WatchOnce() client.WatchChan {
+	return DefalutClient.Watch(context.Background(), OnceDir, client.WithPrefix())
+}
+
+// WatchKiller 监听强杀请求目录 KillerPrefix。
+func WatchKiller() client.WatchChan {
+	return DefalutClient.Watch(context.Background(), KillerPrefix, client.WithPrefix())
+}
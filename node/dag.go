@@ -0,0 +1,238 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3/concurrency"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+const (
+	dagElectionPrefix = "/cronsun/dag/leader"
+
+	// dagWindow 是父任务成功完成到子任务被触发之间允许的最大间隔，
+	// 用来让同一批并行跑在不同结点上的父任务有机会都完成。
+	dagWindow = 5 * time.Minute
+
+	dagSessionTTL = 15
+)
+
+// dagCoordinator 是集群里唯一的一个协调者（通过 etcd lease 选主），
+// 负责监听任务执行结果，在一个 job 被分配到的所有结点都在 dagWindow 内
+// 报告成功后，触发 DependsOn 里列出了它的子任务（TriggerOnSuccess 不参与判断）。
+//
+// 一个 job 往往会被同时分配给好几个结点（比如挂在一个 group 下），
+// 只有其中一个结点报告成功并不代表整个 job 完成了——还有其它结点可能
+// 没跑完，或者跑完了但失败了。所以这里按 jobID -> nodeID -> 完成时间
+// 记录，判断"parent 就绪"时要求它当前的 AssignedNodeIDs 都在窗口内报告
+// 过成功，而不是随便一个结点报告一次就算数。
+type dagCoordinator struct {
+	n    *Node
+	done chan struct{}
+
+	mu      sync.Mutex
+	success map[string]map[string]time.Time // jobID -> nodeID -> 完成时间
+
+	// triggered 记录每个子任务最近一次被触发时用的"截止时间"，用来给
+	// 触发做代际门槛：只有当一个子任务依赖的所有 parent、所有被分配的
+	// 结点，都在这之后重新报告过成功，才认为出现了一整轮新的、完整的
+	// parent 集合完成，才允许再次触发。否则一个 parent（尤其是调度周期
+	// 比 dagWindow 还短的 parent）每 tick 一次就会把同一轮完成重复上报
+	// 成"新完成"，导致子任务被无意义地反复触发。
+	triggered map[string]time.Time
+}
+
+func newDagCoordinator(n *Node) *dagCoordinator {
+	return &dagCoordinator{
+		n:         n,
+		done:      make(chan struct{}),
+		success:   make(map[string]map[string]time.Time, 16),
+		triggered: make(map[string]time.Time, 16),
+	}
+}
+
+// Run 参与选主，选上之后开始消费任务结果流；没选上就一直等待直到当选或 Stop。
+func (c *dagCoordinator) Run() {
+	sess, err := concurrency.NewSession(c.n.Client.Client, concurrency.WithTTL(dagSessionTTL))
+	if err != nil {
+		log.Warnf("dag: new session err: %s", err.Error())
+		return
+	}
+	defer sess.Close()
+
+	election := concurrency.NewElection(sess, dagElectionPrefix)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c.done
+		cancel()
+	}()
+
+	if err := election.Campaign(ctx, c.n.ID); err != nil {
+		if ctx.Err() == nil {
+			log.Warnf("dag: campaign err: %s", err.Error())
+		}
+		return
+	}
+	defer election.Resign(context.Background())
+
+	log.Noticef("dag: %s elected as coordinator", c.n.ID)
+	c.consume(ctx)
+}
+
+func (c *dagCoordinator) Stop() {
+	close(c.done)
+}
+
+// consume 监听 job 执行结果流，成功时记录下来并尝试触发子任务。
+func (c *dagCoordinator) consume(ctx context.Context) {
+	rch := models.WatchJobResult()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, ok := <-rch:
+			if !ok {
+				return
+			}
+
+			if result.Success {
+				c.onParentSuccess(result.JobID, result.NodeID)
+			}
+		}
+	}
+}
+
+func (c *dagCoordinator) onParentSuccess(jobID, nodeID string) {
+	jobs, err := models.GetJobs()
+	if err != nil {
+		log.Warnf("dag: get jobs err: %s", err.Error())
+		return
+	}
+
+	groups, err := models.GetGroups("")
+	if err != nil {
+		log.Warnf("dag: get groups err: %s", err.Error())
+		return
+	}
+
+	nodeIDs, err := models.GetNodes()
+	if err != nil {
+		log.Warnf("dag: get nodes err: %s", err.Error())
+		return
+	}
+
+	c.mu.Lock()
+	nodes, ok := c.success[jobID]
+	if !ok {
+		nodes = make(map[string]time.Time, 4)
+		c.success[jobID] = nodes
+	}
+	nodes[nodeID] = time.Now()
+	c.prune()
+	c.mu.Unlock()
+
+	for _, job := range jobs {
+		if !contains(job.DependsOn, jobID) {
+			continue
+		}
+
+		c.mu.Lock()
+		since := c.triggered[job.ID]
+		c.mu.Unlock()
+
+		if c.parentsReady(job.DependsOn, jobs, groups, nodeIDs, since) {
+			c.trigger(job.ID)
+		}
+	}
+}
+
+// prune 丢弃滚动窗口之外的历史成功记录，避免旧的成功状态被误当作刚完成。
+func (c *dagCoordinator) prune() {
+	cutoff := time.Now().Add(-dagWindow)
+	for jobID, nodes := range c.success {
+		for nodeID, t := range nodes {
+			if t.Before(cutoff) {
+				delete(nodes, nodeID)
+			}
+		}
+		if len(nodes) == 0 {
+			delete(c.success, jobID)
+		}
+	}
+}
+
+// parentsReady 要求每一个 parent job 当前被分配到的所有结点（按照最新
+// 的 groups/nodeIDs 展开）都在 dagWindow 内报告过成功，而不是随便一个
+// 结点报告一次就算这个 parent 完成了。
+//
+// since 是这个子任务上一次被触发时用的门槛时间（从未触发过则是零值）：
+// 所有被分配结点的成功时间都必须晚于 since，才认为这是一整轮"新的"
+// parent 集合完成，而不是同一轮完成状态在 dagWindow 内被重复计入。
+func (c *dagCoordinator) parentsReady(parents []string, jobs models.Jobs, groups models.Groups, nodeIDs []string, since time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range parents {
+		parent, ok := jobs[p]
+		if !ok {
+			return false
+		}
+
+		assigned := parent.AssignedNodeIDs(groups, nodeIDs)
+		if len(assigned) == 0 {
+			return false
+		}
+
+		nodes := c.success[p]
+		for _, id := range assigned {
+			t, done := nodes[id]
+			if !done || !t.After(since) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// trigger 为一个子任务写一条一次性执行请求，复用请求 #chunk0-2 里的 once
+// 机制，并记下这次触发的门槛时间，在此之前的 parent 成功记录都不再算数，
+// 避免同一轮完成状态被重复触发。
+func (c *dagCoordinator) trigger(jobID string) {
+	now := time.Now()
+	req := onceRequest{
+		JobID: jobID,
+		Token: fmt.Sprintf("dag-%s-%d", jobID, now.Unix()),
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		log.Warnf("dag: marshal trigger[%s] err: %s", jobID, err.Error())
+		return
+	}
+
+	key := models.OnceDir + jobID
+	if _, err := c.n.Client.Put(context.Background(), key, string(b)); err != nil {
+		log.Warnf("dag: trigger[%s] err: %s", jobID, err.Error())
+		return
+	}
+
+	c.mu.Lock()
+	c.triggered[jobID] = now
+	c.mu.Unlock()
+}
+
+func contains(list []string, id string) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
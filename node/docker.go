@@ -0,0 +1,83 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+// dockerClient 是对官方 docker client 的薄封装，只暴露 dockerExecutor 需要的两个操作。
+type dockerClient struct {
+	cli *client.Client
+}
+
+func newDockerClient() (*dockerClient, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	return &dockerClient{cli: cli}, nil
+}
+
+func (d *dockerClient) PullImage(ctx context.Context, image string) error {
+	rc, err := d.cli.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(ioutil.Discard, rc)
+	return err
+}
+
+// RunContainer 创建、启动容器，等待其退出后返回日志和退出码。
+func (d *dockerClient) RunContainer(ctx context.Context, cfg *models.DockerJob) (stdout, stderr string, exitCode int, err error) {
+	created, err := d.cli.ContainerCreate(ctx, &container.Config{
+		Image: cfg.Image,
+		Cmd:   cfg.Cmd,
+		Env:   cfg.Env,
+	}, &container.HostConfig{
+		Binds: cfg.Mounts,
+	}, nil, nil, "")
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer d.cli.ContainerRemove(context.Background(), created.ID, types.ContainerRemoveOptions{Force: true})
+
+	if err = d.cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return "", "", -1, err
+	}
+
+	statusCh, errCh := d.cli.ContainerWait(ctx, created.ID, container.WaitConditionNotRunning)
+	select {
+	case err = <-errCh:
+		if err != nil {
+			return "", "", -1, err
+		}
+	case status := <-statusCh:
+		exitCode = int(status.StatusCode)
+	}
+
+	logs, err := d.cli.ContainerLogs(ctx, created.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", "", exitCode, err
+	}
+	defer logs.Close()
+
+	// 容器没有分配 tty（上面 container.Config 没设 Tty），docker 会把
+	// stdout/stderr 按 8 字节帧头 + payload 的格式复用在同一个流里，
+	// 必须用 stdcopy 解出来，直接 io.Copy 会把协议头和两路输出的内容
+	// 混在一起写进 stdout，stderr 也就永远是空的。
+	var stdoutBuf, stderrBuf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdoutBuf, &stderrBuf, logs); err != nil {
+		return "", "", exitCode, err
+	}
+	return stdoutBuf.String(), stderrBuf.String(), exitCode, nil
+}
@@ -0,0 +1,183 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+// Executor 是一种任务运行方式的抽象，models.Job.Kind 决定使用哪一种实现。
+// 这让 cronsun 不再局限于本机 shell 命令，也可以调度 HTTP/gRPC 接口或 Docker 容器。
+type Executor interface {
+	Execute(ctx context.Context, job *models.Job) (stdout, stderr string, exitCode int, err error)
+}
+
+// executorFor 按 job.Kind 选出对应的 Executor，kind 未知或为空时返回 nil，
+// 调用方应当回退到原本的 shell 执行路径。
+func executorFor(kind string) Executor {
+	switch kind {
+	case models.JobKindHTTP:
+		return httpExecutor{}
+	case models.JobKindGRPC:
+		return grpcExecutor{}
+	case models.JobKindDocker:
+		return dockerExecutor{}
+	default:
+		return nil
+	}
+}
+
+// runCmd 执行一次 cmd，shell 类型（Kind 为空或 "shell"）沿用 models.Cmd.Run，
+// 其余类型分发给对应的 Executor。t 是本次调度的触发时间，shell 路径会
+// 原样转给 Cmd.Run 用作 runID 的一部分。
+//
+// 非 shell 的 Executor 没有 *exec.Cmd 可言，但同样要能被 kill.go 强杀：
+// 这里用一个可取消的 ctx 包住 Execute，并和 Cmd.Run 一样把自己登记到
+// Tracker（cmd 传 nil，killOne 发现 re.cmd == nil 时会直接调 cancel），
+// 否则 HTTP/gRPC/Docker 任务的 kill 请求会是悄无声息的空操作。
+func runCmd(cmd *models.Cmd, t time.Time) {
+	exec := executorFor(cmd.Job.Kind)
+	if exec == nil {
+		cmd.Run(t)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := cmd.GetID() + "/" + strconv.FormatInt(t.UnixNano(), 10)
+	if cmd.Tracker != nil {
+		cmd.Tracker.Track(key, cancel, nil)
+		defer cmd.Tracker.Untrack(key)
+	}
+
+	stdout, stderr, code, err := exec.Execute(ctx, cmd.Job)
+	if err != nil {
+		log.Warnf("job[%s] kind[%s] execute err: %s", cmd.Job.ID, cmd.Job.Kind, err.Error())
+	}
+	cmd.WriteResult(stdout, stderr, code, err)
+}
+
+// dispatchCmd 让普通（非 singleton）调度也走 Kind 分发，而不是固定调用
+// models.Cmd.Run。
+type dispatchCmd struct {
+	*models.Cmd
+}
+
+func (c *dispatchCmd) Run(t time.Time) {
+	runCmd(c.Cmd, t)
+}
+
+// httpExecutor 按 job.HTTP 的配置发起一次 HTTP 请求，Body 先按模板渲染；
+// job.Command 不参与。
+type httpExecutor struct{}
+
+func (httpExecutor) Execute(ctx context.Context, job *models.Job) (string, string, int, error) {
+	body, err := renderTemplate(job.HTTP.Body, job.Env)
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	req, err := http.NewRequest(job.HTTP.Method, job.HTTP.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return "", "", -1, err
+	}
+	req = req.WithContext(ctx)
+
+	for k, v := range job.HTTP.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", resp.StatusCode, err
+	}
+
+	if !job.HTTP.IsSuccess(resp.StatusCode) {
+		return string(b), "", resp.StatusCode, fmt.Errorf("http status %d not in success predicate", resp.StatusCode)
+	}
+
+	return string(b), "", resp.StatusCode, nil
+}
+
+func renderTemplate(tpl string, env []string) (string, error) {
+	if tpl == "" {
+		return "", nil
+	}
+
+	t, err := template.New("body").Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := make(map[string]string, len(env))
+	for _, kv := range env {
+		for i := 0; i < len(kv); i++ {
+			if kv[i] == '=' {
+				data[kv[:i]] = kv[i+1:]
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// grpcExecutor 通过 grpc-reflection 动态调用一个 unary 方法。
+type grpcExecutor struct{}
+
+func (grpcExecutor) Execute(ctx context.Context, job *models.Job) (string, string, int, error) {
+	conn, err := grpc.DialContext(ctx, job.GRPC.Target, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer conn.Close()
+
+	reply, err := invokeViaReflection(ctx, conn, job.GRPC.Service, job.GRPC.Method, job.GRPC.Request)
+	if err != nil {
+		return "", "", -1, err
+	}
+	return reply, "", 0, nil
+}
+
+// dockerExecutor 用配置的镜像/命令跑一个容器并收集日志。
+type dockerExecutor struct{}
+
+func (dockerExecutor) Execute(ctx context.Context, job *models.Job) (string, string, int, error) {
+	cli, err := newDockerClient()
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	if job.Docker.PullPolicy != models.PullPolicyNever {
+		if err := cli.PullImage(ctx, job.Docker.Image); err != nil {
+			return "", "", -1, err
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 24*time.Hour)
+	defer cancel()
+
+	return cli.RunContainer(runCtx, job.Docker)
+}
@@ -0,0 +1,61 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// invokeViaReflection 通过服务端的 grpc-reflection 找到 service/method 对应的
+// message 定义，把 JSON 请求体组装成动态消息并发起一次 unary 调用。
+func invokeViaReflection(ctx context.Context, conn *grpc.ClientConn, service, method, reqJSON string) (string, error) {
+	client := grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(conn))
+	defer client.Reset()
+
+	svcDesc, err := client.ResolveService(service)
+	if err != nil {
+		return "", fmt.Errorf("resolve service[%s]: %s", service, err)
+	}
+
+	mDesc := svcDesc.FindMethodByName(method)
+	if mDesc == nil {
+		return "", fmt.Errorf("method[%s] not found on service[%s]", method, service)
+	}
+
+	req := dynamic.NewMessage(mDesc.GetInputType())
+	if reqJSON != "" {
+		if err := req.UnmarshalJSON([]byte(reqJSON)); err != nil {
+			return "", fmt.Errorf("unmarshal request: %s", err)
+		}
+	}
+
+	stub := grpcdynamic.NewStub(conn)
+	resp, err := stub.InvokeRpc(ctx, mDesc, req)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := marshalDynamic(resp, mDesc)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+func marshalDynamic(msg interface{}, mDesc *desc.MethodDescriptor) (string, error) {
+	dm, ok := msg.(*dynamic.Message)
+	if !ok {
+		b, err := json.Marshal(msg)
+		return string(b), err
+	}
+
+	b, err := dm.MarshalJSON()
+	return string(b), err
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+// killGrace 是 SIGTERM 之后等待进程退出的宽限期，超时后补发 SIGKILL。
+const killGrace = 10 * time.Second
+
+// runningExec 记录一次正在执行的任务，用于强制终止。
+type runningExec struct {
+	cancel context.CancelFunc
+	cmd    *exec.Cmd
+
+	// done 在 Untrack 时关闭，让 killOne 能在进程已经自己退出时立刻
+	// 返回，不用死等满 killGrace。
+	done chan struct{}
+}
+
+// Track 实现 models.CmdTracker，在任务开始执行时把它登记到 n.running。
+func (n *Node) Track(key string, cancel context.CancelFunc, cmd *exec.Cmd) {
+	n.runningMu.Lock()
+	n.running[key] = &runningExec{cancel: cancel, cmd: cmd, done: make(chan struct{})}
+	n.runningMu.Unlock()
+}
+
+// Untrack 实现 models.CmdTracker，在任务结束（无论成功/失败/被杀）后移除登记。
+func (n *Node) Untrack(key string) {
+	n.runningMu.Lock()
+	if re, ok := n.running[key]; ok {
+		close(re.done)
+		delete(n.running, key)
+	}
+	n.runningMu.Unlock()
+}
+
+// watchKill 监听 /cronsun/killer/<jobID>，收到请求后强杀本结点上匹配的运行实例。
+//
+// 只响应 Create/Modify。Delete 既会在操作者撤销一个 kill 请求时触发，也会
+// 在它的 lease 正常到期（这正是请求里要求的自过期机制）时触发，两种情况
+// 都不应该被当成"再杀一次"处理，否则每个 kill 请求都会在 TTL 到期后重复
+// 触发一次，撤销操作反而变成了再杀一次。
+func (n *Node) watchKill() {
+	rch := models.WatchKiller()
+	for wresp := range rch {
+		for _, ev := range wresp.Events {
+			if !ev.IsCreate() && !ev.IsModify() {
+				continue
+			}
+
+			jobID := models.GetIDFromKey(string(ev.Kv.Key))
+			n.killJob(jobID)
+		}
+	}
+}
+
+// cancelAllRunning 取消当前结点上所有正在执行的任务实例，在 Node.Stop
+// 时调用，避免进程退出后这些执行（尤其是没有子进程、单靠 ctx 取消的
+// HTTP/gRPC/Docker executor）变成孤儿继续跑下去。
+func (n *Node) cancelAllRunning() {
+	n.runningMu.Lock()
+	defer n.runningMu.Unlock()
+
+	for _, re := range n.running {
+		re.cancel()
+	}
+}
+
+// killJob 终止当前结点上属于 jobID 的所有运行实例。每个实例各自的
+// killOne 最长会阻塞一个宽限期，并发触发，否则 N 个实例会把 watchKill
+// 唯一的消费者 goroutine 堵上 N*killGrace，导致排在后面、本该互不相关
+// 的 kill 请求被一起拖慢。
+func (n *Node) killJob(jobID string) {
+	n.runningMu.Lock()
+	var matched []*runningExec
+	for key, re := range n.running {
+		if runningExecJobID(key) == jobID {
+			matched = append(matched, re)
+		}
+	}
+	n.runningMu.Unlock()
+
+	for _, re := range matched {
+		go n.killOne(re)
+	}
+}
+
+// killOne 先发 SIGTERM，给宽限期后仍未退出则 cancel context 触发 SIGKILL；
+// 进程在宽限期内自己退出（Untrack 关闭 re.done）则立刻返回，不用死等满。
+func (n *Node) killOne(re *runningExec) {
+	if re.cmd == nil || re.cmd.Process == nil {
+		re.cancel()
+		return
+	}
+
+	if err := re.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		log.Warnf("kill: send SIGTERM err: %s", err.Error())
+	}
+
+	timer := time.NewTimer(killGrace)
+	defer timer.Stop()
+	select {
+	case <-re.done:
+	case <-timer.C:
+		re.cancel()
+	}
+}
+
+// runningExecJobID 提取 key（格式 jobID+"/"+ruleID+"/"+runID，即
+// models.Cmd.GetID() 再拼上一截 runID）中最前面的 jobID 部分。
+func runningExecJobID(key string) string {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '/' {
+			return key[:i]
+		}
+	}
+	return key
+}
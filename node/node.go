@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	client "github.com/coreos/etcd/clientv3"
@@ -11,6 +12,7 @@ import (
 	"sunteng/commons/log"
 	"sunteng/commons/util"
 	"github.com/shunfei/cronsun/conf"
+	"github.com/shunfei/cronsun/gitsync"
 	"github.com/shunfei/cronsun/models"
 	"github.com/shunfei/cronsun/node/cron"
 )
@@ -29,6 +31,13 @@ type Node struct {
 	// 删除的 job id，用于 group 更新
 	delIDs map[string]bool
 
+	gitSync *gitsync.GitSync
+	dag     *dagCoordinator
+
+	// 正在执行的任务实例，用于响应强制终止请求
+	running   map[string]*runningExec
+	runningMu sync.Mutex
+
 	ttl  int64
 	lID  client.LeaseID // lease id
 	done chan struct{}
@@ -54,9 +63,14 @@ func NewNode(cfg *conf.Conf) (n *Node, err error) {
 		link:   newLink(8),
 		delIDs: make(map[string]bool, 8),
 
+		gitSync: gitsync.New(ip.String(), cfg.GitSync),
+
+		running: make(map[string]*runningExec, 8),
+
 		ttl:  cfg.Ttl,
 		done: make(chan struct{}),
 	}
+	n.dag = newDagCoordinator(n)
 	return
 }
 
@@ -218,7 +232,14 @@ func (n *Node) addCmd(cmd *models.Cmd, notice bool) {
 		c = cmd
 	}
 
-	n.Cron.Schedule(c.JobRule.Schedule, c)
+	c.Tracker = n
+	c.NodeID = n.ID
+
+	if c.JobRule.Singleton {
+		n.Cron.Schedule(c.JobRule.Schedule, &singletonCmd{c})
+	} else {
+		n.Cron.Schedule(c.JobRule.Schedule, &dispatchCmd{c})
+	}
 	if !ok {
 		n.cmds[c.GetID()] = c
 	}
@@ -411,27 +432,6 @@ func (n *Node) watchGroups() {
 	}
 }
 
-func (n *Node) watchOnce() {
-	rch := models.WatchOnce()
-	for wresp := range rch {
-		for _, ev := range wresp.Events {
-			switch {
-			case ev.IsCreate(), ev.IsModify():
-				if len(ev.Kv.Value) != 0 && string(ev.Kv.Value) != n.ID {
-					continue
-				}
-
-				job, ok := n.jobs[models.GetIDFromKey(string(ev.Kv.Key))]
-				if !ok || !job.IsRunOn(n.ID, n.groups) {
-					continue
-				}
-
-				go job.RunWithRecovery()
-			}
-		}
-	}
-}
-
 // 启动服务
 func (n *Node) Run() (err error) {
 	go n.keepAlive()
@@ -450,6 +450,11 @@ func (n *Node) Run() (err error) {
 	go n.watchJobs()
 	go n.watchGroups()
 	go n.watchOnce()
+	go n.watchKill()
+	if n.gitSync != nil {
+		go n.gitSync.Run()
+	}
+	go n.dag.Run()
 	n.Node.On()
 	return
 }
@@ -458,6 +463,11 @@ func (n *Node) Run() (err error) {
 func (n *Node) Stop(i interface{}) {
 	n.Node.Down()
 	close(n.done)
+	if n.gitSync != nil {
+		n.gitSync.Stop()
+	}
+	n.dag.Stop()
+	n.cancelAllRunning()
 	n.Node.Del()
 	n.Client.Close()
 	n.Cron.Stop()
@@ -0,0 +1,164 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+const (
+	// 去重 token 占用 etcd key 的 ttl，超过这个时间同一个 token 允许重新触发
+	onceTokenTTL = 60
+
+	onceResultDir = "/cronsun/once/result/"
+	onceTokenDir  = "/cronsun/once/token/"
+)
+
+// onceRequest 是写在 /cronsun/once/<jobID> 下的一次性执行请求。
+type onceRequest struct {
+	JobID string `json:"job_id"`
+	// NodeIDs 为空且 Group 为空时，表示该 job 当前分配到的所有结点都执行
+	NodeIDs   []string          `json:"node_ids,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// Deadline 为 unix 时间戳（秒），<=0 表示不设超时
+	Deadline int64  `json:"deadline,omitempty"`
+	Token    string `json:"token"`
+}
+
+// onceResult 是执行完成后写回 /cronsun/once/result/<token> 的结果。
+type onceResult struct {
+	NodeID   string `json:"node_id"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+	Err      string `json:"err,omitempty"`
+}
+
+// matches 判断当前结点是否是本次一次性执行的目标。
+func (req *onceRequest) matches(n *Node) bool {
+	if len(req.NodeIDs) == 0 && req.Group == "" {
+		return true
+	}
+
+	for _, id := range req.NodeIDs {
+		if id == n.ID {
+			return true
+		}
+	}
+
+	if req.Group == "" {
+		return false
+	}
+
+	g, ok := n.groups[req.Group]
+	return ok && g.Included(n.ID)
+}
+
+// watchOnce 监听一次性执行请求，匹配到当前结点后触发 runOnce。
+func (n *Node) watchOnce() {
+	rch := models.WatchOnce()
+	for wresp := range rch {
+		for _, ev := range wresp.Events {
+			if !ev.IsCreate() && !ev.IsModify() {
+				continue
+			}
+
+			req := new(onceRequest)
+			if err := json.Unmarshal(ev.Kv.Value, req); err != nil {
+				log.Warnf("once[%s] bad request: %s", string(ev.Kv.Key), err.Error())
+				continue
+			}
+
+			if !req.matches(n) {
+				continue
+			}
+
+			// n.cmds 是按 job+rule 的复合 key 索引的，一次性请求按 job id
+			// 下发，要先拿到这个 job，再展开成它在本结点上的所有 Cmd
+			// （和 addJob/delJob 的做法一致），不能直接拿 job id 去查 n.cmds。
+			job, ok := n.jobs[req.JobID]
+			if !ok {
+				continue
+			}
+
+			for _, cmd := range job.Cmds(n.ID, n.groups) {
+				go n.runOnce(cmd, req)
+			}
+		}
+	}
+}
+
+// runOnce 执行一次性请求，负责去重、超时控制和结果上报。
+func (n *Node) runOnce(cmd *models.Cmd, req *onceRequest) {
+	if req.Token != "" && !n.claimOnceToken(req.Token) {
+		return
+	}
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if req.Deadline > 0 {
+		ctx, cancel = context.WithDeadline(ctx, time.Unix(req.Deadline, 0))
+		defer cancel()
+	}
+
+	job := *cmd.Job
+	for k, v := range req.Overrides {
+		job.Env = append(job.Env, k+"="+v)
+	}
+
+	result := onceResult{NodeID: n.ID}
+	out, err := job.RunWithContext(ctx)
+	result.Output = out
+	if err != nil {
+		result.Err = err.Error()
+		result.ExitCode = 1
+	}
+
+	n.publishOnceResult(req.Token, &result)
+}
+
+// claimOnceToken 用 lease 占用的方式保证同一个 token 在同一个结点上只被
+// 执行一次（重复投递/重试时去重）。key 必须按结点维度区分——一次性请求
+// 可能同时指向一个 group 里的多个结点，这些结点都是合法的执行目标，
+// 不能让它们抢同一把锁。
+func (n *Node) claimOnceToken(token string) bool {
+	resp, err := n.Client.Grant(onceTokenTTL)
+	if err != nil {
+		log.Warnf("once token[%s] grant lease err: %s", token, err.Error())
+		return false
+	}
+
+	key := onceTokenDir + token + "/" + n.ID
+	txn := n.Client.Txn(context.Background())
+	txnResp, err := txn.If(client.Compare(client.CreateRevision(key), "=", 0)).
+		Then(client.OpPut(key, n.ID, client.WithLease(resp.ID))).
+		Commit()
+	if err != nil {
+		log.Warnf("once token[%s] claim err: %s", token, err.Error())
+		return false
+	}
+
+	return txnResp.Succeeded
+}
+
+func (n *Node) publishOnceResult(token string, result *onceResult) {
+	if token == "" {
+		return
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		log.Warnf("once token[%s] marshal result err: %s", token, err.Error())
+		return
+	}
+
+	if _, err := n.Client.Put(context.Background(), onceResultDir+token, string(b)); err != nil {
+		log.Warnf("once token[%s] publish result err: %s", token, err.Error())
+	}
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd/clientv3/concurrency"
+
+	"sunteng/commons/log"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+// lockDir 是 singleton 任务抢锁使用的 etcd 前缀。
+const lockDir = "/cronsun/lock/"
+
+// defaultLockTTL 在 JobRule.LockTTL 未设置时使用。
+// 锁所在的 concurrency.Session 在执行期间会自动续约，所以 TTL 不限制
+// 任务的执行时长，只决定持锁结点失联后锁多久才会过期。
+const defaultLockTTL = 60
+
+// singletonCmd 包装一个 *models.Cmd，让它在执行前先抢占一把按 fire 时间
+// 划分的分布式锁，从而保证同一次调度只有一个结点真正执行。
+// 抢锁失败时直接跳过本次执行，不会重试、不会报错。
+type singletonCmd struct {
+	*models.Cmd
+}
+
+// Run 覆盖 models.Cmd.Run，先尝试抢锁，抢到才真正执行。
+func (c *singletonCmd) Run(t time.Time) {
+	ttl := c.JobRule.LockTTL
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+
+	// 把锁的 key 和这次调度的触发时间（精确到秒）绑定，这样同一次调度
+	// 在所有结点上算出来的 key 是一样的，不同次调度互不影响。t 必须是
+	// node/cron 引擎派发这次 tick 时用的那个时间，而不是各结点各自读
+	// 一遍的 time.Now()：两个结点哪怕只有几十毫秒的时钟偏差或调度抖动，
+	// 跨过一个秒的边界就会独立算出两个不同的 key，各自抢到各自的锁，
+	// 于是两边都会执行——正好是 singleton 要防止的重复执行。
+	key := fmt.Sprintf("%s%s/%d", lockDir, c.Job.ID, t.Truncate(time.Second).Unix())
+
+	sess, err := concurrency.NewSession(models.DefalutClient.Client, concurrency.WithTTL(ttl))
+	if err != nil {
+		log.Warnf("job[%s] singleton: new session err: %s", c.Job.ID, err.Error())
+		return
+	}
+	defer sess.Close()
+
+	mutex := concurrency.NewMutex(sess, key)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := mutex.TryLock(ctx); err != nil {
+		// 没抢到锁，说明其它结点已经在跑这次调度，本结点静默跳过
+		return
+	}
+	defer mutex.Unlock(context.Background())
+
+	runCmd(c.Cmd, t)
+}
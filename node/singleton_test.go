@@ -0,0 +1,78 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+
+	"github.com/shunfei/cronsun/models"
+)
+
+// newTestEtcdClient 连接本地 etcd，连不上就跳过测试——这是一个需要真实
+// etcd 的集成测试，跑之前要先起一个 etcd（默认 127.0.0.1:2379）。
+func newTestEtcdClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	cli, err := client.New(client.Config{
+		Endpoints:   []string{"127.0.0.1:2379"},
+		DialTimeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Skipf("etcd not available: %s", err)
+	}
+
+	if _, err := cli.Get(context.Background(), "health-check"); err != nil {
+		t.Skipf("etcd not available: %s", err)
+	}
+	return cli
+}
+
+// TestSingletonCmdRunOnlyOnce 模拟 N 个结点在同一个 fire time 上抢同一个
+// singleton job，验证不管起多少个结点一起抢，最终只有一个真正执行。
+// 每个模拟结点各自用自己的 singletonCmd 包装同一个 job+rule，像真实场景
+// 里每个结点读到的是各自 etcd watch 来的同一份 job 定义。
+func TestSingletonCmdRunOnlyOnce(t *testing.T) {
+	cli := newTestEtcdClient(t)
+	defer cli.Close()
+	models.DefalutClient = &models.Client{Client: cli}
+
+	out, err := ioutil.TempFile("", "cronsun-singleton-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	const nodeCount = 8
+	fireTime := time.Now()
+
+	job := &models.Job{ID: "singleton-test-job", Command: "echo ran >> " + out.Name()}
+	rule := &models.JobRule{ID: "r1", Singleton: true, LockTTL: 5}
+
+	var wg sync.WaitGroup
+	wg.Add(nodeCount)
+	for i := 0; i < nodeCount; i++ {
+		go func() {
+			defer wg.Done()
+			cmd := &singletonCmd{&models.Cmd{Job: job, JobRule: rule}}
+			cmd.Run(fireTime)
+		}()
+	}
+	wg.Wait()
+
+	b, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := bytes.Count(b, []byte("ran\n"))
+	if got != 1 {
+		t.Fatalf("expected exactly 1 node to run the job, got %d", got)
+	}
+}